@@ -0,0 +1,122 @@
+// Copyright 2018 Josh Lubawy. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slip
+
+import (
+	"fmt"
+	"io"
+)
+
+// A ChecksumType selects the checksum, if any, appended as a trailer to a
+// SLIP frame's payload.
+type ChecksumType int
+
+const (
+	// ChecksumNone disables the checksum trailer. This is the zero value,
+	// so existing Encodings are unaffected unless Checksum is set.
+	ChecksumNone ChecksumType = iota
+
+	// ChecksumFletcher16 appends a 16-bit Fletcher checksum of the payload.
+	ChecksumFletcher16
+
+	// ChecksumCRC16CCITT appends a 16-bit CRC of the payload computed with
+	// polynomial 0x1021, initial value 0xFFFF, no input or output
+	// reflection, and no final XOR (the CCITT-FALSE variant).
+	ChecksumCRC16CCITT
+)
+
+// A ChecksumMismatchError is returned when a decoded packet's checksum
+// trailer doesn't match the checksum recomputed over its payload.
+type ChecksumMismatchError struct {
+	Want, Got uint16
+}
+
+func (e ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("slip: checksum mismatch, want 0x%04X got 0x%04X", e.Want, e.Got)
+}
+
+// a checksumState accumulates a checksum one byte at a time, so that it can
+// be used both to checksum a whole buffer at once and to checksum a stream
+// as it's written or read.
+type checksumState struct {
+	typ        ChecksumType
+	sum1, sum2 uint16 // Fletcher16
+	crc        uint16 // CRC16CCITT
+}
+
+func newChecksumState(typ ChecksumType) *checksumState {
+	cs := &checksumState{typ: typ}
+	if typ == ChecksumCRC16CCITT {
+		cs.crc = 0xFFFF
+	}
+	return cs
+}
+
+func (cs *checksumState) update(b byte) {
+	switch cs.typ {
+	case ChecksumFletcher16:
+		cs.sum1 = (cs.sum1 + uint16(b)) % 255
+		cs.sum2 = (cs.sum2 + cs.sum1) % 255
+	case ChecksumCRC16CCITT:
+		cs.crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if cs.crc&0x8000 != 0 {
+				cs.crc = (cs.crc << 1) ^ 0x1021
+			} else {
+				cs.crc <<= 1
+			}
+		}
+	}
+}
+
+func (cs *checksumState) sum() uint16 {
+	switch cs.typ {
+	case ChecksumFletcher16:
+		return cs.sum2<<8 | cs.sum1
+	case ChecksumCRC16CCITT:
+		return cs.crc
+	default:
+		return 0
+	}
+}
+
+// checksum computes the checksum of payload according to enc.Checksum. It
+// returns 0 if enc.Checksum is ChecksumNone.
+func (enc *Encoding) checksum(payload []byte) uint16 {
+	cs := newChecksumState(enc.Checksum)
+	for _, b := range payload {
+		cs.update(b)
+	}
+	return cs.sum()
+}
+
+// trailerBytes returns the big-endian checksum trailer that Encode appends
+// after src, or nil if enc.Checksum is ChecksumNone.
+func (enc *Encoding) trailerBytes(src []byte) []byte {
+	if enc.Checksum == ChecksumNone {
+		return nil
+	}
+	sum := enc.checksum(src)
+	return []byte{byte(sum >> 8), byte(sum)}
+}
+
+// stripChecksum verifies and removes the checksum trailer from a decoded
+// packet. It returns decoded unchanged if enc.Checksum is ChecksumNone.
+func (enc *Encoding) stripChecksum(decoded []byte) (payload []byte, err error) {
+	if enc.Checksum == ChecksumNone {
+		return decoded, nil
+	}
+	if len(decoded) < 2 {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	payload = decoded[:len(decoded)-2]
+	want := uint16(decoded[len(decoded)-2])<<8 | uint16(decoded[len(decoded)-1])
+	got := enc.checksum(payload)
+	if want != got {
+		return nil, ChecksumMismatchError{Want: want, Got: got}
+	}
+	return payload, nil
+}
@@ -0,0 +1,87 @@
+// Copyright 2018 Josh Lubawy. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slip
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChecksumRoundTrip(t *testing.T) {
+	stdFletcher16 := &Encoding{
+		Start:    StartDisabled,
+		EscStart: StartDisabled,
+		End:      0xC0,
+		EscEnd:   0xDC,
+		Esc:      0xDB,
+		EscEsc:   0xDD,
+		Checksum: ChecksumFletcher16,
+	}
+
+	var cases = []struct {
+		enc     *Encoding
+		payload []byte
+	}{
+		{enc: StdEncodingCRC16, payload: mustDecodeHex("010203C0DBDD")},
+		{enc: BluefruitEncodingCRC16, payload: mustDecodeHex("010203BCCDAC")},
+		{enc: stdFletcher16, payload: mustDecodeHex("010203C0DBDD")},
+	}
+
+	for i, tc := range cases {
+		t.Logf("Test case %d", i)
+
+		encoded := tc.enc.Encode(tc.payload)
+
+		dst, n, err := tc.enc.Decode(encoded)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !bytes.Equal(tc.payload, dst[:n]) {
+			t.Errorf("payload mismatch: expected=% X actual=% X", tc.payload, dst[:n])
+		}
+
+		scanner := NewScanner(tc.enc, bytes.NewReader(encoded))
+		if !scanner.Scan() {
+			t.Fatalf("expected a packet, got none (err=%v)", scanner.Err())
+		}
+		if !bytes.Equal(tc.payload, scanner.Bytes()) {
+			t.Errorf("scanner payload mismatch: expected=% X actual=% X", tc.payload, scanner.Bytes())
+		}
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	encoded := StdEncodingCRC16.Encode(mustDecodeHex("010203"))
+
+	// Flip every bit of the first payload byte so the trailing checksum no
+	// longer matches.
+	encoded[0] ^= 0xFF
+
+	if _, _, err := StdEncodingCRC16.Decode(encoded); err == nil {
+		t.Fatal("expected a ChecksumMismatchError")
+	} else if _, ok := err.(ChecksumMismatchError); !ok {
+		t.Errorf("expected ChecksumMismatchError, got %T: %v", err, err)
+	}
+
+	scanner := NewScanner(StdEncodingCRC16, bytes.NewReader(encoded))
+	if scanner.Scan() {
+		t.Fatal("expected scanning to fail on checksum mismatch")
+	}
+	if _, ok := scanner.Err().(ChecksumMismatchError); !ok {
+		t.Errorf("expected ChecksumMismatchError, got %T: %v", scanner.Err(), scanner.Err())
+	}
+}
+
+func TestStreamChecksumMismatch(t *testing.T) {
+	encoded := StdEncodingCRC16.Encode(mustDecodeHex("010203"))
+	encoded[0] ^= 0xFF
+
+	r := NewDecoder(StdEncodingCRC16, bytes.NewReader(encoded))
+	buf := make([]byte, len(encoded))
+	_, err := r.Read(buf)
+	if _, ok := err.(ChecksumMismatchError); !ok {
+		t.Errorf("expected ChecksumMismatchError, got %T: %v", err, err)
+	}
+}
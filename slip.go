@@ -24,6 +24,25 @@ type Encoding struct {
 	Start, EscStart rune
 	End, EscEnd     rune
 	Esc, EscEsc     rune
+
+	// MaxPacketSize, if non-zero, bounds the number of bytes SplitPackets
+	// will buffer while looking for an End character before treating the
+	// input as overflowed. This lets a noisy line be recovered from without
+	// relying on bufio.Scanner's own, much larger, MaxScanTokenSize limit.
+	MaxPacketSize int
+
+	// ResyncOnOverflow, if true, causes the scanners returned by NewScanner
+	// and NewScannerWithOptions to recover from an overflowed packet, an
+	// InvalidControlCharError, or a ChecksumMismatchError by discarding
+	// input up to and including the next End character instead of returning
+	// an error that stops the scanner. SplitPackets itself panics when it is
+	// set.
+	ResyncOnOverflow bool
+
+	// Checksum selects an optional checksum trailer appended after the
+	// payload and before the End character. It defaults to ChecksumNone,
+	// leaving framing-only Encodings unaffected.
+	Checksum ChecksumType
 }
 
 var StdEncoding = &Encoding{
@@ -44,6 +63,28 @@ var BluefruitEncoding = &Encoding{
 	EscEsc:   0xCE,
 }
 
+// StdEncodingCRC16 is StdEncoding with a ChecksumCRC16CCITT trailer.
+var StdEncodingCRC16 = &Encoding{
+	Start:    StartDisabled,
+	EscStart: StartDisabled,
+	End:      0xC0,
+	EscEnd:   0xDC,
+	Esc:      0xDB,
+	EscEsc:   0xDD,
+	Checksum: ChecksumCRC16CCITT,
+}
+
+// BluefruitEncodingCRC16 is BluefruitEncoding with a ChecksumCRC16CCITT trailer.
+var BluefruitEncodingCRC16 = &Encoding{
+	Start:    0xAB,
+	EscStart: 0xAC,
+	End:      0xBC,
+	EscEnd:   0xBD,
+	Esc:      0xCD,
+	EscEsc:   0xCE,
+	Checksum: ChecksumCRC16CCITT,
+}
+
 // An InvalidControlCharError is returned when a non-control character follows
 // an Escape character. It gives the index in the byte slice where the character
 // was found, and which character it was.
@@ -56,87 +97,285 @@ func (e InvalidControlCharError) Error() string {
 	return fmt.Sprintf("invalid control character 0x%02X Escaped at index %d", e.ControlChar, e.Index)
 }
 
+// A ResyncError is reported through the onResync callback passed to
+// NewScannerWithOptions when the scanner discards input while recovering
+// from an overflowed or malformed packet; it is never returned as an error. Dropped is the number
+// of bytes that were discarded, up to and including the End character that
+// resynchronization stopped at.
+type ResyncError struct {
+	Dropped int
+}
+
+func (e ResyncError) Error() string {
+	return fmt.Sprintf("slip: resynced after dropping %d bytes", e.Dropped)
+}
+
 var _ bufio.SplitFunc = (*Encoding)(nil).SplitPackets
 
 // SplitPackets is a split function for a bufio.Scanner that returns a packet
 // for each token.
+//
+// SplitPackets panics if enc.ResyncOnOverflow is true. Recovering from an
+// overflowed packet spans multiple calls to the split function, but
+// scanner.Split(enc.SplitPackets) hands bufio.Scanner a new, stateless
+// closure on every call, so that recovery state could never carry across
+// Scan calls — the scanner would silently hand back corrupted data instead
+// of resyncing. Use NewScanner or NewScannerWithOptions instead, which keep
+// a single split function alive for the scanner's lifetime.
 func (enc *Encoding) SplitPackets(data []byte, atEOF bool) (advance int, token []byte, err error) {
-	EndIndex := -1
-	tokenByteCount := 0
-	for i := 0; i < len(data); i++ {
-		r := rune(data[i])
-		if r == enc.End {
-			EndIndex = i
-			break
-		} else if r != enc.Esc {
-			tokenByteCount += 1
+	if enc.ResyncOnOverflow {
+		panic("slip: SplitPackets cannot support ResyncOnOverflow; use NewScanner or NewScannerWithOptions instead")
+	}
+	return enc.splitPackets(nil)(data, atEOF)
+}
+
+// splitPackets returns a bufio.SplitFunc that behaves like SplitPackets, but
+// reports any resync performed while recovering from MaxPacketSize overflow
+// or an InvalidControlCharError through onResync, if non-nil, instead of
+// through its returned error.
+func (enc *Encoding) splitPackets(onResync func(ResyncError)) bufio.SplitFunc {
+	// resyncing and dropped track an overflow recovery that spans more than
+	// one call to the returned SplitFunc: once the confirmed-garbage prefix
+	// of an oversized packet has been dropped, the search for the End
+	// character that ends the garbled run continues call-by-call, still
+	// bounded by MaxPacketSize each time, instead of waiting for it to show
+	// up anywhere in the still-growing buffer.
+	resyncing := false
+	dropped := 0
+
+	report := func() {
+		if onResync != nil {
+			onResync(ResyncError{Dropped: dropped})
 		}
+		dropped = 0
 	}
-	if EndIndex == -1 {
-		if atEOF {
-			advance = len(data)
-			token = data
-			err = io.EOF
+
+	// findEnd looks for the next End character, bounded by MaxPacketSize
+	// unless atEOF: once the underlying reader is exhausted, data holds all
+	// that's ever going to arrive, so there's no more unbounded-buffer risk
+	// to guard against and the whole of it can be searched.
+	findEnd := func(data []byte, atEOF bool) (idx, limit int, overflowed bool) {
+		limit = len(data)
+		if !atEOF && enc.MaxPacketSize > 0 && limit > enc.MaxPacketSize {
+			limit = enc.MaxPacketSize
+			overflowed = true
+		}
+		idx = -1
+		for i := 0; i < limit; i++ {
+			if rune(data[i]) == enc.End {
+				idx = i
+				break
+			}
 		}
 		return
 	}
 
-	StartIndex := 0
-	if enc.Start != StartDisabled {
-		if rune(data[0]) == enc.Start {
-			StartIndex = 1
-			tokenByteCount -= 1
+	// split is declared as a var so it can call itself: once atEOF, a
+	// bufio.Scanner gives the SplitFunc exactly one more call before giving
+	// up, even if that call only advances without producing a token. So
+	// when a resync resolves at EOF, split recurses on what's left of data
+	// to parse the next packet immediately, instead of returning a token-
+	// less result and stalling the scan.
+	var split bufio.SplitFunc
+	split = func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		if resyncing {
+			idx, limit, overflowed := findEnd(data, atEOF)
+			if idx == -1 {
+				if overflowed {
+					// Still nothing but garbage in this chunk; drop it and
+					// keep resyncing on the next call instead of asking for
+					// more data, which is what let the buffer grow past
+					// bufio.Scanner's own MaxScanTokenSize in the first
+					// place.
+					dropped += limit
+					advance = limit
+					return
+				}
+				if atEOF {
+					dropped += len(data)
+					advance = len(data)
+					err = io.EOF
+					resyncing = false
+					report()
+				}
+				return
+			}
+
+			dropped += idx + 1
+			resyncing = false
+			report()
+			if atEOF {
+				subAdvance, subToken, subErr := split(data[idx+1:], true)
+				return idx + 1 + subAdvance, subToken, subErr
+			}
+			advance = idx + 1
+			return
 		}
-	}
 
-	// Advance past the End character
-	advance = EndIndex + 1
-	token = make([]byte, tokenByteCount)
+		searchLimit := len(data)
+		overflowed := false
+		if !atEOF && enc.MaxPacketSize > 0 && len(data) > enc.MaxPacketSize {
+			searchLimit = enc.MaxPacketSize
+			overflowed = true
+		}
 
-	// Decode the input
-	inEscSeq := false
-	j := 0
-	for i := StartIndex; i < EndIndex; i++ {
-		r := rune(data[i])
-		if inEscSeq {
-			if !enc.isValidControlEscChar(data[i]) {
-				err = InvalidControlCharError{i, data[i]}
+		EndIndex := -1
+		tokenByteCount := 0
+		for i := 0; i < searchLimit; i++ {
+			r := rune(data[i])
+			if r == enc.End {
+				EndIndex = i
+				overflowed = false
+				break
+			} else if r != enc.Esc {
+				tokenByteCount += 1
+			}
+		}
+		if EndIndex == -1 {
+			if overflowed && enc.ResyncOnOverflow {
+				// The first searchLimit bytes are confirmed to hold no End
+				// character, so they're safe to drop immediately rather
+				// than buffering them alongside whatever comes next while
+				// waiting for an End to eventually show up.
+				resyncing = true
+				dropped = searchLimit
+				advance = searchLimit
 				return
 			}
+			if atEOF {
+				advance = len(data)
+				token = data
+				err = io.EOF
+			}
+			return
+		}
 
-			inEscSeq = false
+		StartIndex := 0
+		if enc.Start != StartDisabled {
+			if rune(data[0]) == enc.Start {
+				StartIndex = 1
+				tokenByteCount -= 1
+			}
+		}
 
-			switch r {
-			case enc.EscStart:
-				token[j] = byte(enc.Start)
-				j += 1
-			case enc.EscEnd:
-				token[j] = byte(enc.End)
-				j += 1
-			case enc.EscEsc:
-				token[j] = byte(enc.Esc)
-				j += 1
-			default:
+		// Advance past the End character
+		advance = EndIndex + 1
+		token = make([]byte, tokenByteCount)
+
+		// Decode the input
+		inEscSeq := false
+		j := 0
+		for i := StartIndex; i < EndIndex; i++ {
+			r := rune(data[i])
+			if inEscSeq {
+				if !enc.isValidControlEscChar(data[i]) {
+					if enc.ResyncOnOverflow {
+						return enc.resync(data, EndIndex, atEOF, onResync)
+					}
+					err = InvalidControlCharError{i, data[i]}
+					return
+				}
+
+				inEscSeq = false
+
+				switch r {
+				case enc.EscStart:
+					token[j] = byte(enc.Start)
+					j += 1
+				case enc.EscEnd:
+					token[j] = byte(enc.End)
+					j += 1
+				case enc.EscEsc:
+					token[j] = byte(enc.Esc)
+					j += 1
+				default:
+					return
+				}
+			} else {
+				switch r {
+				case enc.Esc:
+					inEscSeq = true
+				default:
+					token[j] = data[i]
+					j += 1
+				}
+			}
+		}
+
+		if enc.Checksum != ChecksumNone {
+			payload, cerr := enc.stripChecksum(token[:j])
+			if cerr != nil {
+				if enc.ResyncOnOverflow {
+					return enc.resync(data, EndIndex, atEOF, onResync)
+				}
+				err = cerr
 				return
 			}
-		} else {
-			switch r {
-			case enc.Esc:
-				inEscSeq = true
-			default:
-				token[j] = data[i]
-				j += 1
+			token = payload
+		}
+
+		return
+	}
+	return split
+}
+
+// resync discards data up to and including the next End character found at
+// or after from, reporting the number of dropped bytes through onResync if
+// non-nil. It's used once a token's own End character has already been
+// found but its contents turned out to be unrecoverable (a bad escape
+// sequence or a checksum mismatch), so the search below always resolves on
+// its first iteration; it does not need to span multiple calls to the
+// SplitFunc the way recovering from a MaxPacketSize overflow does.
+func (enc *Encoding) resync(data []byte, from int, atEOF bool, onResync func(ResyncError)) (advance int, token []byte, err error) {
+	idx := -1
+	for i := from; i < len(data); i++ {
+		if rune(data[i]) == enc.End {
+			idx = i
+			break
+		}
+	}
+
+	if idx == -1 {
+		if atEOF && len(data) > from {
+			advance = len(data)
+			if onResync != nil {
+				onResync(ResyncError{Dropped: advance})
 			}
+			err = io.EOF
 		}
+		return
 	}
 
+	advance = idx + 1
+	if onResync != nil {
+		onResync(ResyncError{Dropped: advance})
+	}
 	return
 }
 
-// NewScanner returns a new bufio.Scanner with the split function set to SplitPackets.
+// NewScanner returns a new bufio.Scanner with the split function set to
+// SplitPackets. Unlike calling scanner.Split(enc.SplitPackets) directly,
+// this keeps a single split function across the scanner's lifetime, so an
+// overflow recovery spanning multiple Scan calls (see MaxPacketSize) works
+// correctly.
+//
+// Resyncs are otherwise invisible through NewScanner: dropped bytes are
+// neither returned through scanner.Err() nor reported anywhere else. Callers
+// that want to observe ResyncErrors should use NewScannerWithOptions instead.
 func NewScanner(enc *Encoding, r io.Reader) *bufio.Scanner {
 	scanner := bufio.NewScanner(r)
-	scanner.Split(enc.SplitPackets)
+	scanner.Split(enc.splitPackets(nil))
+	return scanner
+}
+
+// NewScannerWithOptions returns a new bufio.Scanner that, like NewScanner,
+// honors enc.MaxPacketSize and enc.ResyncOnOverflow, and additionally
+// reports each resync. When a resync occurs, onResync is called with the
+// number of bytes that were dropped; onResync may be nil if the caller
+// doesn't care.
+func NewScannerWithOptions(enc *Encoding, r io.Reader, onResync func(ResyncError)) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Split(enc.splitPackets(onResync))
 	return scanner
 }
 
@@ -179,14 +418,30 @@ func (enc *Encoding) controlCharCount(src []byte) (count int) {
 }
 
 // EncodedLen returns the size of the destination buffer needed to encode a
-// buffer for a given encoding.
+// buffer for a given encoding, including its checksum trailer, if any.
 func (enc *Encoding) EncodedLen(src []byte) int {
-	return len(src) + enc.minLength() + enc.controlCharCount(src)
+	trailer := enc.trailerBytes(src)
+	return len(src) + len(trailer) + enc.minLength() + enc.controlCharCount(src) + enc.controlCharCount(trailer)
 }
 
 // Encode encodes the given data as a SLIP message.
 func (enc *Encoding) Encode(src []byte) (dst []byte) {
 	dst = make([]byte, enc.EncodedLen(src))
+	enc.EncodeTo(dst, src)
+	return
+}
+
+// EncodeTo encodes src as a SLIP message into dst, returning the number of
+// bytes written. If enc.Checksum is set, the checksum trailer is computed
+// over src and appended, escaped like any other byte, before the End
+// character. EncodeTo panics if dst is not large enough to hold the result;
+// callers should size dst with EncodedLen. EncodeTo allows callers to reuse
+// a buffer across calls instead of allocating on every frame.
+func (enc *Encoding) EncodeTo(dst, src []byte) (n int) {
+	trailer := enc.trailerBytes(src)
+	if len(dst) < enc.EncodedLen(src) {
+		panic("slip: dst buffer too small, must be at least len(EncodedLen(src))")
+	}
 
 	j := 0
 	if enc.Start != StartDisabled {
@@ -194,10 +449,10 @@ func (enc *Encoding) Encode(src []byte) (dst []byte) {
 		j += 1
 	}
 
-	for i := 0; i < len(src) && (j < len(dst)-1); i++ {
-		if enc.isValidControlChar(src[i]) {
+	writeEscaped := func(b byte) {
+		if enc.isValidControlChar(b) {
 			dst[j] = byte(enc.Esc)
-			switch rune(src[i]) {
+			switch rune(b) {
 			case enc.Start:
 				dst[j+1] = byte(enc.EscStart)
 			case enc.End:
@@ -207,11 +462,91 @@ func (enc *Encoding) Encode(src []byte) (dst []byte) {
 			}
 			j += 2
 		} else {
-			dst[j] = src[i]
+			dst[j] = b
 			j += 1
 		}
 	}
+
+	for i := 0; i < len(src); i++ {
+		writeEscaped(src[i])
+	}
+	for i := 0; i < len(trailer); i++ {
+		writeEscaped(trailer[i])
+	}
 	dst[j] = byte(enc.End)
+	j += 1
+
+	return j
+}
+
+// DecodedLen returns an upper bound on the size of the destination buffer
+// needed to decode a SLIP message for a given encoding. Since escaped
+// control characters only ever shrink during decoding, len(src) is always
+// sufficient.
+func (enc *Encoding) DecodedLen(src []byte) int {
+	return len(src)
+}
+
+// Decode decodes src, which must hold exactly one framed SLIP message
+// (optional Start byte through End byte), returning the decoded payload.
+func (enc *Encoding) Decode(src []byte) (dst []byte, n int, err error) {
+	dst = make([]byte, enc.DecodedLen(src))
+	n, err = enc.DecodeTo(dst, src)
+	dst = dst[:n]
+	return
+}
+
+// DecodeTo decodes src, which must hold exactly one framed SLIP message
+// (optional Start byte through End byte), into dst, returning the number of
+// bytes written. It returns io.ErrUnexpectedEOF if src does not end with the
+// End character, InvalidControlCharError if it contains a bad escape
+// sequence, and ChecksumMismatchError if enc.Checksum is set and the
+// trailer doesn't match the decoded payload. DecodeTo allows callers to
+// reuse a buffer across calls instead of allocating on every frame.
+func (enc *Encoding) DecodeTo(dst, src []byte) (n int, err error) {
+	if len(src) < enc.minLength() || rune(src[len(src)-1]) != enc.End {
+		err = io.ErrUnexpectedEOF
+		return
+	}
+
+	StartIndex := 0
+	if enc.Start != StartDisabled && rune(src[0]) == enc.Start {
+		StartIndex = 1
+	}
+	EndIndex := len(src) - 1
+
+	inEscSeq := false
+	for i := StartIndex; i < EndIndex; i++ {
+		b := src[i]
+		if inEscSeq {
+			if !enc.isValidControlEscChar(b) {
+				err = InvalidControlCharError{i, b}
+				return
+			}
+			inEscSeq = false
+
+			switch rune(b) {
+			case enc.EscStart:
+				dst[n] = byte(enc.Start)
+			case enc.EscEnd:
+				dst[n] = byte(enc.End)
+			case enc.EscEsc:
+				dst[n] = byte(enc.Esc)
+			}
+			n += 1
+		} else if rune(b) == enc.Esc {
+			inEscSeq = true
+		} else {
+			dst[n] = b
+			n += 1
+		}
+	}
+
+	payload, err := enc.stripChecksum(dst[:n])
+	if err != nil {
+		return 0, err
+	}
+	n = len(payload)
 
 	return
 }
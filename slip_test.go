@@ -5,6 +5,7 @@
 package slip
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/hex"
 	"strings"
@@ -144,3 +145,171 @@ func TestStandardEncode(t *testing.T) {
 		}
 	}
 }
+
+func TestStandardDecode(t *testing.T) {
+	var cases = []struct {
+		input  []byte
+		output []byte
+	}{
+		{
+			input:  mustDecodeHex("010203 DBDC DC DBDD DD C0"),
+			output: mustDecodeHex("010203 C0   DC DB   DD"),
+		},
+	}
+
+	for i, tc := range cases {
+		t.Logf("Test case %d", i)
+
+		dst, n, err := StdEncoding.Decode(tc.input)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if n != len(tc.output) {
+			t.Errorf("expected %d decoded bytes but got %d", len(tc.output), n)
+		}
+		if !bytes.Equal(tc.output, dst) {
+			t.Error("data mismatch")
+			t.Errorf("expected: %X", tc.output)
+			t.Errorf("actual  : %X", dst)
+		}
+	}
+}
+
+func TestEncodeToPanicsWhenDstTooSmall(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected EncodeTo to panic when dst is too small")
+		}
+	}()
+
+	src := mustDecodeHex("010203")
+	dst := make([]byte, StdEncoding.EncodedLen(src)-1)
+	StdEncoding.EncodeTo(dst, src)
+}
+
+func TestResyncOnOverflow(t *testing.T) {
+	enc := &Encoding{
+		Start:            StdEncoding.Start,
+		EscStart:         StdEncoding.EscStart,
+		End:              StdEncoding.End,
+		EscEnd:           StdEncoding.EscEnd,
+		Esc:              StdEncoding.Esc,
+		EscEsc:           StdEncoding.EscEsc,
+		MaxPacketSize:    4,
+		ResyncOnOverflow: true,
+	}
+
+	// The first "packet" is longer than MaxPacketSize and has no End byte
+	// until well past the limit; it should be dropped and scanning should
+	// resync on the following, well-formed packet.
+	input := mustDecodeHex("0102030405060708C0 0102C0")
+
+	var resyncs []ResyncError
+	scanner := NewScannerWithOptions(enc, bytes.NewReader(input), func(e ResyncError) {
+		resyncs = append(resyncs, e)
+	})
+
+	var actuals [][]byte
+	for scanner.Scan() {
+		pkt := make([]byte, len(scanner.Bytes()))
+		copy(pkt, scanner.Bytes())
+		actuals = append(actuals, pkt)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resyncs) != 1 {
+		t.Fatalf("expected 1 resync, got %d", len(resyncs))
+	}
+	if resyncs[0].Dropped != 9 {
+		t.Errorf("expected 9 dropped bytes, got %d", resyncs[0].Dropped)
+	}
+
+	want := [][]byte{mustDecodeHex("0102")}
+	if len(actuals) != len(want) {
+		t.Fatalf("expected %d packets, got %d", len(want), len(actuals))
+	}
+	if !bytes.Equal(want[0], actuals[0]) {
+		t.Errorf("expected=% X actual=% X", want[0], actuals[0])
+	}
+}
+
+func TestResyncOnOverflowBoundsScannerBuffer(t *testing.T) {
+	enc := &Encoding{
+		Start:            StdEncoding.Start,
+		EscStart:         StdEncoding.EscStart,
+		End:              StdEncoding.End,
+		EscEnd:           StdEncoding.EscEnd,
+		Esc:              StdEncoding.Esc,
+		EscEsc:           StdEncoding.EscEsc,
+		MaxPacketSize:    4,
+		ResyncOnOverflow: true,
+	}
+
+	// A garbled run well past bufio.MaxScanTokenSize, with no End byte of
+	// its own until the very end, followed by a well-formed packet. Before
+	// the overflow fix, waiting for that End to show up anywhere in the
+	// still-growing buffer meant the scanner buffer grew past
+	// bufio.MaxScanTokenSize and Scan failed with bufio.ErrTooLong.
+	garbage := bytes.Repeat([]byte{0x01}, 100*1024)
+	input := append(append(append([]byte{}, garbage...), 0xC0), mustDecodeHex("0102C0")...)
+
+	var resyncs []ResyncError
+	scanner := NewScannerWithOptions(enc, bytes.NewReader(input), func(e ResyncError) {
+		resyncs = append(resyncs, e)
+	})
+
+	var actuals [][]byte
+	for scanner.Scan() {
+		pkt := make([]byte, len(scanner.Bytes()))
+		copy(pkt, scanner.Bytes())
+		actuals = append(actuals, pkt)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resyncs) != 1 {
+		t.Fatalf("expected 1 resync, got %d", len(resyncs))
+	}
+	if want := len(garbage) + 1; resyncs[0].Dropped != want {
+		t.Errorf("expected %d dropped bytes, got %d", want, resyncs[0].Dropped)
+	}
+
+	want := [][]byte{mustDecodeHex("0102")}
+	if len(actuals) != len(want) {
+		t.Fatalf("expected %d packets, got %d", len(want), len(actuals))
+	}
+	if !bytes.Equal(want[0], actuals[0]) {
+		t.Errorf("expected=% X actual=% X", want[0], actuals[0])
+	}
+}
+
+func TestSplitPacketsPanicsWithResyncOnOverflow(t *testing.T) {
+	enc := &Encoding{
+		Start:            StdEncoding.Start,
+		EscStart:         StdEncoding.EscStart,
+		End:              StdEncoding.End,
+		EscEnd:           StdEncoding.EscEnd,
+		Esc:              StdEncoding.Esc,
+		EscEsc:           StdEncoding.EscEsc,
+		MaxPacketSize:    4,
+		ResyncOnOverflow: true,
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected scanner.Split(enc.SplitPackets) to panic with ResyncOnOverflow set")
+		}
+	}()
+
+	// scanner.Split(enc.SplitPackets) hands bufio.Scanner a fresh, stateless
+	// closure on every call, so an overflow resync spanning multiple Scan
+	// calls can't be tracked; before the panic this silently handed back a
+	// corrupted token instead of resyncing.
+	input := mustDecodeHex("0102030405060708C0 0102C0")
+	scanner := bufio.NewScanner(bytes.NewReader(input))
+	scanner.Split(enc.SplitPackets)
+	scanner.Scan()
+}
@@ -0,0 +1,248 @@
+// Copyright 2018 Josh Lubawy. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slip
+
+import (
+	"bufio"
+	"io"
+)
+
+// An encoder implements io.WriteCloser, writing SLIP-encoded data to an
+// underlying io.Writer.
+type encoder struct {
+	enc *Encoding
+	w   io.Writer
+
+	wroteStart bool
+	buf        [2]byte
+	sum        *checksumState
+}
+
+// NewEncoder returns a new io.WriteCloser that SLIP-encodes bytes written to
+// it and writes the result to w. The Start character (if used by enc) is
+// written before the first encoded byte, whether that happens on the first
+// call to Write or, for an empty payload, on Close; the End character is
+// always written by Close. If enc.Checksum is set, the checksum trailer is
+// accumulated as bytes are written and appended before the End character on
+// Close. The returned writer must be closed in order to terminate the
+// frame.
+func NewEncoder(enc *Encoding, w io.Writer) io.WriteCloser {
+	e := &encoder{enc: enc, w: w}
+	if enc.Checksum != ChecksumNone {
+		e.sum = newChecksumState(enc.Checksum)
+	}
+	return e
+}
+
+// writeRaw writes b to the underlying writer unescaped, for use with the
+// literal Start and End characters.
+func (e *encoder) writeRaw(b byte) error {
+	e.buf[0] = b
+	_, err := e.w.Write(e.buf[:1])
+	return err
+}
+
+// writeEscaped writes b to the underlying writer, escaping it first if it
+// collides with one of enc's control characters.
+func (e *encoder) writeEscaped(b byte) error {
+	if e.enc.isValidControlChar(b) {
+		e.buf[0] = byte(e.enc.Esc)
+		switch rune(b) {
+		case e.enc.Start:
+			e.buf[1] = byte(e.enc.EscStart)
+		case e.enc.End:
+			e.buf[1] = byte(e.enc.EscEnd)
+		case e.enc.Esc:
+			e.buf[1] = byte(e.enc.EscEsc)
+		}
+		_, err := e.w.Write(e.buf[:2])
+		return err
+	}
+	return e.writeRaw(b)
+}
+
+// Write implements io.Writer, escaping control characters as necessary
+// before writing the encoded bytes to the underlying writer.
+func (e *encoder) Write(p []byte) (n int, err error) {
+	if !e.wroteStart {
+		if e.enc.Start != StartDisabled {
+			if err = e.writeRaw(byte(e.enc.Start)); err != nil {
+				return 0, err
+			}
+		}
+		e.wroteStart = true
+	}
+
+	for n = 0; n < len(p); n++ {
+		if e.sum != nil {
+			e.sum.update(p[n])
+		}
+		if err = e.writeEscaped(p[n]); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Close writes the Start character if Write was never called, appends the
+// checksum trailer if enc.Checksum is set, and writes the End character to
+// the underlying writer, terminating the frame. It does not close the
+// underlying writer.
+func (e *encoder) Close() error {
+	if !e.wroteStart {
+		if e.enc.Start != StartDisabled {
+			if err := e.writeRaw(byte(e.enc.Start)); err != nil {
+				return err
+			}
+		}
+		e.wroteStart = true
+	}
+	if e.sum != nil {
+		sum := e.sum.sum()
+		if err := e.writeEscaped(byte(sum >> 8)); err != nil {
+			return err
+		}
+		if err := e.writeEscaped(byte(sum)); err != nil {
+			return err
+		}
+	}
+	return e.writeRaw(byte(e.enc.End))
+}
+
+// A decoder implements io.Reader, reading one SLIP packet's payload per
+// Read sequence from an underlying io.Reader.
+type decoder struct {
+	enc *Encoding
+	r   *bufio.Reader
+
+	started bool
+	done    bool
+	offset  int
+
+	sum        *checksumState
+	trailer    [2]byte
+	trailerLen int
+}
+
+// NewDecoder returns a new io.Reader that decodes SLIP packets read from r.
+// Read returns the decoded payload of one packet at a time, returning
+// io.EOF once the End character for that packet has been seen. The next
+// call to Read then begins decoding the following packet. io.EOF is
+// returned permanently once the underlying reader is exhausted. If
+// enc.Checksum is set, the trailing checksum is verified and stripped from
+// the payload, and Read returns ChecksumMismatchError if it doesn't match.
+func NewDecoder(enc *Encoding, r io.Reader) io.Reader {
+	return &decoder{enc: enc, r: bufio.NewReader(r)}
+}
+
+// emit delivers a decoded payload byte to p. When enc.Checksum is set, the
+// most recent two decoded bytes are always withheld in d.trailer, since
+// they may turn out to be the checksum trailer rather than payload; the
+// oldest withheld byte, once superseded, is what actually gets delivered.
+// emit is only ever called with room left in p, since the Read loop it's
+// called from checks n < len(p) before decoding the next byte.
+func (d *decoder) emit(b byte, p []byte, n *int) {
+	if d.sum == nil {
+		p[*n] = b
+		*n++
+		return
+	}
+
+	if d.trailerLen < 2 {
+		d.trailer[d.trailerLen] = b
+		d.trailerLen++
+		return
+	}
+
+	p[*n] = d.trailer[0]
+	d.sum.update(d.trailer[0])
+	*n++
+	d.trailer[0] = d.trailer[1]
+	d.trailer[1] = b
+}
+
+// Read implements io.Reader as described in NewDecoder.
+func (d *decoder) Read(p []byte) (n int, err error) {
+	if d.done {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if !d.started {
+		d.offset = 0
+		if d.enc.Start != StartDisabled {
+			b, rerr := d.r.ReadByte()
+			if rerr != nil {
+				d.done = true
+				return 0, rerr
+			}
+			if rune(b) != d.enc.Start {
+				d.r.UnreadByte()
+			} else {
+				d.offset++
+			}
+		}
+		if d.enc.Checksum != ChecksumNone {
+			d.sum = newChecksumState(d.enc.Checksum)
+		}
+		d.trailerLen = 0
+		d.started = true
+	}
+
+	for n < len(p) {
+		b, rerr := d.r.ReadByte()
+		if rerr != nil {
+			d.done = true
+			return n, rerr
+		}
+		d.offset++
+
+		switch rune(b) {
+		case d.enc.End:
+			d.started = false
+			if d.sum != nil && d.trailerLen < 2 {
+				d.done = true
+				return n, io.ErrUnexpectedEOF
+			}
+			if d.sum != nil {
+				want := uint16(d.trailer[0])<<8 | uint16(d.trailer[1])
+				got := d.sum.sum()
+				if want != got {
+					d.done = true
+					return n, ChecksumMismatchError{Want: want, Got: got}
+				}
+			}
+			return n, io.EOF
+		case d.enc.Esc:
+			eb, rerr := d.r.ReadByte()
+			if rerr != nil {
+				d.done = true
+				return n, rerr
+			}
+			d.offset++
+
+			if !d.enc.isValidControlEscChar(eb) {
+				d.done = true
+				return n, InvalidControlCharError{d.offset - 1, eb}
+			}
+
+			var decoded byte
+			switch rune(eb) {
+			case d.enc.EscStart:
+				decoded = byte(d.enc.Start)
+			case d.enc.EscEnd:
+				decoded = byte(d.enc.End)
+			case d.enc.EscEsc:
+				decoded = byte(d.enc.Esc)
+			}
+			d.emit(decoded, p, &n)
+		default:
+			d.emit(b, p, &n)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2018 Josh Lubawy. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slip
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// readPacket reads from r until it returns io.EOF, which NewDecoder uses to
+// mark the end of a single packet.
+func readPacket(r io.Reader) ([]byte, error) {
+	var out []byte
+	buf := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		out = append(out, buf[:n]...)
+		if err != nil {
+			if err == io.EOF {
+				return out, nil
+			}
+			return out, err
+		}
+	}
+}
+
+func TestEncoderDecoder(t *testing.T) {
+	var cases = []struct {
+		enc     *Encoding
+		packets [][]byte
+	}{
+		{
+			enc: StdEncoding,
+			packets: [][]byte{
+				mustDecodeHex("010203"),
+				mustDecodeHex("04C0"),
+				mustDecodeHex("DB05"),
+			},
+		},
+		{
+			enc: BluefruitEncoding,
+			packets: [][]byte{
+				mustDecodeHex("010203"),
+				mustDecodeHex("04ABBC"),
+				mustDecodeHex("CD05"),
+			},
+		},
+		{
+			enc: StdEncodingCRC16,
+			packets: [][]byte{
+				mustDecodeHex("010203"),
+				mustDecodeHex("04C0"),
+				mustDecodeHex("DB05"),
+			},
+		},
+		{
+			enc: BluefruitEncodingCRC16,
+			packets: [][]byte{
+				mustDecodeHex("010203"),
+				mustDecodeHex("04ABBC"),
+				mustDecodeHex("CD05"),
+			},
+		},
+	}
+
+	for i, tc := range cases {
+		t.Logf("Test case %d", i)
+
+		var buf bytes.Buffer
+		for _, pkt := range tc.packets {
+			w := NewEncoder(tc.enc, &buf)
+			if _, err := w.Write(pkt); err != nil {
+				t.Fatalf("unexpected Write error: %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("unexpected Close error: %v", err)
+			}
+		}
+
+		r := NewDecoder(tc.enc, &buf)
+		for j, want := range tc.packets {
+			got, err := readPacket(r)
+			if err != nil {
+				t.Fatalf("unexpected Read error: %v", err)
+			}
+			if !bytes.Equal(want, got) {
+				t.Errorf("packet %d mismatch", j)
+				t.Errorf("   expected=% X", want)
+				t.Errorf("   actual  =% X", got)
+			}
+		}
+	}
+}
+
+func TestEncoderCloseWithoutWrite(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewEncoder(BluefruitEncoding, &buf)
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected Close error: %v", err)
+	}
+
+	want := mustDecodeHex("ABBC")
+	if !bytes.Equal(want, buf.Bytes()) {
+		t.Errorf("expected=% X actual=% X", want, buf.Bytes())
+	}
+}
+
+func TestDecoderInvalidControlCharIndexPerPacket(t *testing.T) {
+	// The second packet's bad escape sits at index 1, not at its position
+	// within the overall stream (index 4).
+	input := mustDecodeHex("0102C0DB01C0")
+	r := NewDecoder(StdEncoding, bytes.NewReader(input))
+
+	if _, err := readPacket(r); err != nil {
+		t.Fatalf("unexpected Read error on first packet: %v", err)
+	}
+
+	_, err := readPacket(r)
+	want := InvalidControlCharError{Index: 1, ControlChar: 0x01}
+	if err != want {
+		t.Errorf("expected=%v actual=%v", want, err)
+	}
+}